@@ -1,13 +1,17 @@
 package datasize
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Size uint64
@@ -18,6 +22,7 @@ const (
 	Gigabyte      = 1000 * Megabyte
 	Terabyte      = 1000 * Gigabyte
 	Petabyte      = 1000 * Terabyte
+	Exabyte       = 1000 * Petabyte
 )
 
 const (
@@ -27,11 +32,34 @@ const (
 	Gibibyte      = 1024 * Mebibyte
 	Tebibyte      = 1024 * Gibibyte
 	Pebibyte      = 1024 * Tebibyte
+	Exbibyte      = 1024 * Pebibyte
 )
 
-var sizeRegex = regexp.MustCompile(`([0-9]*)(\.[0-9]*)?([a-z]+)`)
+// ErrSizeOverflow is returned by Parse when the value, computed in float64,
+// exceeds math.MaxUint64; values at the very top of the range round up to
+// 2^64 and are rejected too. Use ParseBig instead.
+var ErrSizeOverflow = errors.New("datasize: value overflows Size, use ParseBig")
+
+var sizeRegex = regexp.MustCompile(`^\s*([0-9][0-9,]*)?(\.[0-9]*)?\s*([kKmMgGtTpPeEzZyY]?)([iI]?)([bB]?)\s*$`)
+
+// parseSize extracts the numeric value and a suffixes-table key from s, for
+// the fallback path shared by Parse and ParseBig.
+func parseSize(s string) (float64, string, error) {
+	ss := sizeRegex.FindStringSubmatch(s)
+	if len(ss) == 0 {
+		return 0, "", fmt.Errorf("datasize: invalid Size format: %q", s)
+	}
+	digits := strings.ReplaceAll(ss[1], ",", "")
+	f, err := strconv.ParseFloat(digits+ss[2], 64)
+	if err != nil {
+		return 0, "", err
+	}
+	suffix := strings.ToLower(ss[3]) + strings.ToLower(ss[4]) + "b"
+	return f, suffix, nil
+}
 
 var units = []Size{
+	Exbibyte, Exabyte,
 	Pebibyte, Petabyte,
 	Tebibyte, Terabyte,
 	Gibibyte, Gigabyte,
@@ -39,62 +67,150 @@ var units = []Size{
 	Kibibyte, Kilobyte,
 }
 
+// unitSuffix is a recognized suffix's base and power, e.g. "gb" is {1000, 3}.
+type unitSuffix struct {
+	base int64
+	exp  int
+}
+
+var suffixes = map[string]unitSuffix{
+	"b":   {1000, 0},
+	"kb":  {1000, 1},
+	"mb":  {1000, 2},
+	"gb":  {1000, 3},
+	"tb":  {1000, 4},
+	"pb":  {1000, 5},
+	"eb":  {1000, 6},
+	"zb":  {1000, 7},
+	"yb":  {1000, 8},
+	"kib": {1024, 1},
+	"mib": {1024, 2},
+	"gib": {1024, 3},
+	"tib": {1024, 4},
+	"pib": {1024, 5},
+	"eib": {1024, 6},
+	"zib": {1024, 7},
+	"yib": {1024, 8},
+}
+
+// maxUint64Exp is the highest suffix exponent that fits in a uint64 Size.
+const maxUint64Exp = 6
+
+var siNames = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB", "ZB", "YB"}
+var iecNames = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB", "ZiB", "YiB"}
+
+// maxUint64Float is math.MaxUint64 as an exact big.Float: float64(math.MaxUint64)
+// itself rounds up to 2^64, so comparing against it would admit out-of-range values.
+var maxUint64Float = new(big.Float).SetUint64(math.MaxUint64)
+
+func boundedUint64(f float64) (Size, error) {
+	if f < 0 || big.NewFloat(f).Cmp(maxUint64Float) > 0 {
+		return 0, ErrSizeOverflow
+	}
+	return Size(f), nil
+}
+
 func Parse(s string) (Size, error) {
 	if s == "" {
 		return 0, errors.New("datasize: invalid Size: empty")
 	}
 	f, err := strconv.ParseFloat(s, 64)
 	if err == nil {
-		return Size(f), nil
+		return boundedUint64(f)
 	}
-	ss := sizeRegex.FindStringSubmatch(strings.ToLower(s))
-	if len(ss) == 0 {
-		return 0, fmt.Errorf("datasize: invalid Size format: %q", s)
-	}
-	f, err = strconv.ParseFloat(ss[1]+ss[2], 64)
+	f, suffix, err := parseSize(s)
 	if err != nil {
 		return 0, err
 	}
-	sz, err := suffixSize(ss[3])
+	sz, err := suffixSize(suffix)
 	if err != nil {
 		return 0, err
 	}
-	return Size(f * float64(sz)), nil
+	return boundedUint64(f * float64(sz))
+}
+
+// ParseBig is like Parse, but returns an arbitrary-precision result so
+// values beyond Size's uint64 range don't overflow.
+func ParseBig(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("datasize: invalid Size: empty")
+	}
+	if i, ok := new(big.Int).SetString(s, 10); ok {
+		return i, nil
+	}
+	f, suffix, err := parseSize(s)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := suffixes[suffix]
+	if !ok {
+		return nil, fmt.Errorf("datasize: invalid Size unit suffix: %q", suffix)
+	}
+	mul := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(u.base), big.NewInt(int64(u.exp)), nil))
+	result := new(big.Float).Mul(big.NewFloat(f), mul)
+	bi, _ := result.Int(nil)
+	return bi, nil
+}
+
+// FormatBig renders v the way String renders a Size, but with no uint64
+// ceiling (base == 10 for SI, base == 2 for IEC).
+func FormatBig(v *big.Int, base int) string {
+	names := siNames
+	b := big.NewInt(1000)
+	if base == 2 {
+		names = iecNames
+		b = big.NewInt(1024)
+	}
+	if v.Sign() == 0 {
+		return "0" + names[0]
+	}
+	abs := new(big.Int).Abs(v)
+	divisor := big.NewInt(1)
+	exp := 0
+	for exp < len(names)-1 {
+		next := new(big.Int).Mul(divisor, b)
+		if next.Cmp(abs) > 0 {
+			break
+		}
+		divisor = next
+		exp++
+	}
+	quotient := new(big.Float).Quo(new(big.Float).SetInt(v), new(big.Float).SetInt(divisor))
+	return formatBig(quotient, names[exp])
+}
+
+// formatBig renders quotient via big.Float.Text so magnitudes beyond
+// int64/float64 range aren't truncated or misrendered.
+func formatBig(quotient *big.Float, suffix string) string {
+	if quotient.IsInt() {
+		return quotient.Text('f', 0) + suffix
+	}
+	return quotient.Text('f', 2) + suffix
 }
 
 func suffixSize(suffix string) (Size, error) {
-	switch suffix {
-	case "b":
-		return Byte, nil
-	case "kb":
-		return Kilobyte, nil
-	case "mb":
-		return Megabyte, nil
-	case "gb":
-		return Gigabyte, nil
-	case "tb":
-		return Terabyte, nil
-	case "pb":
-		return Petabyte, nil
-	case "kib":
-		return Kibibyte, nil
-	case "mib":
-		return Mebibyte, nil
-	case "gib":
-		return Gibibyte, nil
-	case "tib":
-		return Tebibyte, nil
-	case "pib":
-		return Pebibyte, nil
-	default:
+	u, ok := suffixes[suffix]
+	if !ok {
 		return 0, fmt.Errorf("datasize: invalid Size unit suffix: %q", suffix)
 	}
+	if u.exp > maxUint64Exp {
+		return 0, ErrSizeOverflow
+	}
+	sz := Size(1)
+	for i := 0; i < u.exp; i++ {
+		sz *= Size(u.base)
+	}
+	return sz, nil
 }
 
 func sizeSuffix(unit Size) string {
 	switch unit {
 	default:
 		return "B"
+	case Exabyte:
+		return "EB"
+	case Exbibyte:
+		return "EiB"
 	case Petabyte:
 		return "PB"
 	case Pebibyte:
@@ -136,35 +252,124 @@ func (s Size) Round() Size {
 	return s
 }
 
+// Add returns s+other, saturating at math.MaxUint64 instead of wrapping.
+func (s Size) Add(other Size) Size {
+	sum := s + other
+	if sum < s {
+		return Size(math.MaxUint64)
+	}
+	return sum
+}
+
+// Sub returns s-other, saturating at 0 instead of wrapping.
+func (s Size) Sub(other Size) Size {
+	if other > s {
+		return 0
+	}
+	return s - other
+}
+
+// Mul returns s scaled by n, saturating at math.MaxUint64; a non-positive n
+// saturates to 0.
+func (s Size) Mul(n float64) Size {
+	if n <= 0 {
+		return 0
+	}
+	product := float64(s) * n
+	if product >= float64(math.MaxUint64) {
+		return Size(math.MaxUint64)
+	}
+	return Size(product)
+}
+
+func (s Size) Div(n float64) float64 {
+	return float64(s) / n
+}
+
+func (s Size) Ratio(other Size) float64 {
+	return float64(s) / float64(other)
+}
+
+// Clamp restricts s to the closed range [min, max].
+func (s Size) Clamp(min, max Size) Size {
+	switch {
+	case s < min:
+		return min
+	case s > max:
+		return max
+	default:
+		return s
+	}
+}
+
+// Cmp returns -1 if s < other, 1 if s > other, and 0 otherwise.
+func (s Size) Cmp(other Size) int {
+	switch {
+	case s < other:
+		return -1
+	case s > other:
+		return 1
+	default:
+		return 0
+	}
+}
+
 func (s Size) String() string {
+	v, suffix := s.unit()
+	return format(v, suffix)
+}
+
+func (s Size) unit() (float64, string) {
 	switch {
 	case s == 0:
-		return "0B"
+		return 0, "B"
+	case s%Exabyte == 0:
+		return s.Exabytes(), "EB"
+	case s >= Exbibyte:
+		return s.Exbibytes(), "EiB"
 	case s%Petabyte == 0:
-		return format(s.Petabytes(), "PB")
+		return s.Petabytes(), "PB"
 	case s >= Pebibyte:
-		return format(s.Pebibytes(), "PiB")
+		return s.Pebibytes(), "PiB"
 	case s%Terabyte == 0:
-		return format(s.Terabytes(), "TB")
+		return s.Terabytes(), "TB"
 	case s >= Tebibyte:
-		return format(s.Tebibytes(), "TiB")
+		return s.Tebibytes(), "TiB"
 	case s%Gigabyte == 0:
-		return format(s.Gigabytes(), "GB")
+		return s.Gigabytes(), "GB"
 	case s >= Gibibyte:
-		return format(s.Gibibytes(), "GiB")
+		return s.Gibibytes(), "GiB"
 	case s%Megabyte == 0:
-		return format(s.Megabytes(), "MB")
+		return s.Megabytes(), "MB"
 	case s >= Mebibyte:
-		return format(s.Mebibytes(), "MiB")
+		return s.Mebibytes(), "MiB"
 	case s%Kilobyte == 0:
-		return format(s.Kilobytes(), "kB")
+		return s.Kilobytes(), "kB"
 	case s >= Kibibyte:
-		return format(s.Kibibytes(), "KiB")
+		return s.Kibibytes(), "KiB"
 	default:
-		return fmt.Sprintf("%dB", s)
+		return float64(s), "B"
 	}
 }
 
+func (s Size) siUnit() (float64, string) {
+	for _, unit := range []Size{Exabyte, Petabyte, Terabyte, Gigabyte, Megabyte, Kilobyte} {
+		if s >= unit {
+			return float64(s) / float64(unit), sizeSuffix(unit)
+		}
+	}
+	return float64(s), "B"
+}
+
+func (s Size) iecUnit() (float64, string) {
+	for _, unit := range []Size{Exbibyte, Pebibyte, Tebibyte, Gibibyte, Mebibyte, Kibibyte} {
+		if s >= unit {
+			return float64(s) / float64(unit), sizeSuffix(unit)
+		}
+	}
+	return float64(s), "B"
+}
+
 func format(size float64, suffix string) string {
 	if math.Floor(size) == size {
 		return fmt.Sprintf("%d%s", int64(size), suffix)
@@ -172,6 +377,112 @@ func format(size float64, suffix string) string {
 	return fmt.Sprintf("%.2f%s", size, suffix)
 }
 
+// render picks a unit for s according to base (0 auto, 10 SI-only, 2
+// IEC-only); 'f' always shows prec decimals, 'd'/'s'/'v' behave like String
+// unless prec is given.
+func (s Size) render(verb rune, prec int, space bool, base int) string {
+	var v float64
+	var suffix string
+	switch base {
+	case 10:
+		v, suffix = s.siUnit()
+	case 2:
+		v, suffix = s.iecUnit()
+	default:
+		v, suffix = s.unit()
+	}
+
+	var num string
+	switch verb {
+	case 'f':
+		if prec < 0 {
+			prec = 2
+		}
+		num = strconv.FormatFloat(v, 'f', prec, 64)
+	case 'd', 's', 'v':
+		switch {
+		case prec >= 0:
+			num = strconv.FormatFloat(v, 'f', prec, 64)
+		case math.Floor(v) == v:
+			num = strconv.FormatInt(int64(v), 10)
+		default:
+			num = strconv.FormatFloat(v, 'f', 2, 64)
+		}
+	default:
+		return fmt.Sprintf("%%!%c(datasize.Size=%d)", verb, uint64(s))
+	}
+	if space {
+		return num + " " + suffix
+	}
+	return num + suffix
+}
+
+// Render is Format without going through the fmt package; prec of -1 uses
+// the default precision.
+func (s Size) Render(verb rune, prec int, space bool) string {
+	return s.render(verb, prec, space, 0)
+}
+
+// Format implements fmt.Formatter: %d and %s render like String, %f (and
+// %.Nf) forces floating-point output, and the ' ' flag adds a space before
+// the suffix.
+func (s Size) Format(f fmt.State, verb rune) {
+	s.formatState(f, verb, 0)
+}
+
+func (s Size) formatState(f fmt.State, verb rune, base int) {
+	prec := -1
+	if p, ok := f.Precision(); ok {
+		prec = p
+	}
+	str := s.render(verb, prec, f.Flag(' '), base)
+	if width, ok := f.Width(); ok && len(str) < width {
+		padding := strings.Repeat(" ", width-len(str))
+		if f.Flag('-') {
+			str += padding
+		} else {
+			str = padding + str
+		}
+	}
+	io.WriteString(f, str)
+}
+
+// DecimalSize is a Size that always renders using SI (base-1000) units.
+type DecimalSize Size
+
+// SI returns a view of s that renders using SI units only.
+func (s Size) SI() DecimalSize {
+	return DecimalSize(s)
+}
+
+func (d DecimalSize) String() string {
+	v, suffix := Size(d).siUnit()
+	return format(v, suffix)
+}
+
+// Format implements fmt.Formatter; see Size.Format.
+func (d DecimalSize) Format(f fmt.State, verb rune) {
+	Size(d).formatState(f, verb, 10)
+}
+
+// BinarySize is a Size that always renders using IEC (base-1024) units.
+type BinarySize Size
+
+// IEC returns a view of s that renders using IEC units only.
+func (s Size) IEC() BinarySize {
+	return BinarySize(s)
+}
+
+func (d BinarySize) String() string {
+	v, suffix := Size(d).iecUnit()
+	return format(v, suffix)
+}
+
+// Format implements fmt.Formatter; see Size.Format.
+func (d BinarySize) Format(f fmt.State, verb rune) {
+	Size(d).formatState(f, verb, 2)
+}
+
 func (s Size) Bytes() uint64 {
 	return uint64(s)
 }
@@ -196,6 +507,10 @@ func (s Size) Petabytes() float64 {
 	return float64(s) / float64(Petabyte)
 }
 
+func (s Size) Exabytes() float64 {
+	return float64(s) / float64(Exabyte)
+}
+
 func (s Size) Kibibytes() float64 {
 	return float64(s) / float64(Kibibyte)
 }
@@ -216,6 +531,49 @@ func (s Size) Pebibytes() float64 {
 	return float64(s) / float64(Pebibyte)
 }
 
+func (s Size) Exbibytes() float64 {
+	return float64(s) / float64(Exbibyte)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Size) UnmarshalText(text []byte) error {
+	sz, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sz
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, always encoding as a string (e.g.
+// "512MiB").
+func (s Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either a JSON string
+// ("512MiB") or a JSON number (a raw byte count); null is a no-op.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return s.UnmarshalText([]byte(str))
+	}
+	var n uint64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("datasize: invalid Size JSON: %s", data)
+	}
+	*s = Size(n)
+	return nil
+}
+
 type sizeFlag struct {
 	*Size
 }
@@ -247,3 +605,118 @@ func (f *sizeFlag) Set(s string) error {
 	*f.Size = sz
 	return nil
 }
+
+// Rate is a transfer rate, stored as bytes per second.
+type Rate float64
+
+// Per returns the average rate of transferring s over d; a non-positive d
+// returns zero instead of dividing by zero.
+func (s Size) Per(d time.Duration) Rate {
+	if d <= 0 {
+		return 0
+	}
+	return Rate(float64(s) / d.Seconds())
+}
+
+// rateTier pairs the decimal and binary units checked at one magnitude when
+// rendering a Rate, mirroring the precedence Size.String() uses.
+type rateTier struct {
+	decimal, binary       Size
+	decimalSfx, binarySfx string
+}
+
+var rateTiers = []rateTier{
+	{Exabyte, Exbibyte, "EB", "EiB"},
+	{Petabyte, Pebibyte, "PB", "PiB"},
+	{Terabyte, Tebibyte, "TB", "TiB"},
+	{Gigabyte, Gibibyte, "GB", "GiB"},
+	{Megabyte, Mebibyte, "MB", "MiB"},
+	{Kilobyte, Kibibyte, "kB", "KiB"},
+}
+
+func floatUnit(v float64) (float64, string) {
+	if v == 0 {
+		return 0, "B"
+	}
+	for _, t := range rateTiers {
+		if math.Mod(v, float64(t.decimal)) == 0 {
+			return v / float64(t.decimal), t.decimalSfx
+		}
+		if v >= float64(t.binary) {
+			return v / float64(t.binary), t.binarySfx
+		}
+	}
+	return v, "B"
+}
+
+func (r Rate) String() string {
+	v := float64(r)
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	val, suffix := floatUnit(v)
+	num := strconv.FormatFloat(val, 'f', 2, 64)
+	num = strings.TrimRight(strings.TrimRight(num, "0"), ".")
+	return sign + num + suffix + "/s"
+}
+
+var rateRegex = regexp.MustCompile(`(?i)^\s*(.+?)\s*/\s*(s|sec|ms|min|h|hr)\s*$`)
+
+var rateIntervals = map[string]time.Duration{
+	"s":   time.Second,
+	"sec": time.Second,
+	"ms":  time.Millisecond,
+	"min": time.Minute,
+	"h":   time.Hour,
+	"hr":  time.Hour,
+}
+
+// ParseRate parses strings like "12.5MiB/s", "800kB/sec" or "1.2GB/min" into
+// bytes per second.
+func ParseRate(s string) (Rate, error) {
+	ss := rateRegex.FindStringSubmatch(s)
+	if len(ss) == 0 {
+		return 0, fmt.Errorf("datasize: invalid Rate format: %q", s)
+	}
+	sz, err := Parse(ss[1])
+	if err != nil {
+		return 0, err
+	}
+	interval := rateIntervals[strings.ToLower(ss[2])]
+	return sz.Per(interval), nil
+}
+
+type rateFlag struct {
+	*Rate
+}
+
+// FlagRate is the Rate equivalent of Flag, for bandwidth-limit CLI flags.
+func FlagRate(name, value, description string) *Rate {
+	r, err := ParseRate(value)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid Rate value for flag --%q: %q", name, value))
+	}
+	return FlagVarRate(flag.CommandLine, &r, name, r, description)
+}
+
+// FlagVarRate is the Rate equivalent of FlagVar.
+func FlagVarRate(fs *flag.FlagSet, r *Rate, name string, value Rate, description string) *Rate {
+	*r = value
+	f := &rateFlag{r}
+	fs.Var(f, name, description)
+	return f.Rate
+}
+
+func (f *rateFlag) Get() any {
+	return *f.Rate
+}
+
+func (f *rateFlag) Set(s string) error {
+	r, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*f.Rate = r
+	return nil
+}
@@ -1,8 +1,14 @@
 package datasize
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParse(t *testing.T) {
@@ -67,6 +73,274 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		sz     Size
+		str    string
+	}{
+		{"%d", Gigabyte, "1GB"},
+		{"%s", 12345678 * Byte, "11.77MiB"},
+		{"%f", Gibibyte, "1.00GiB"},
+		{"%.2f", 12345678 * Byte, "11.77MiB"},
+		{"% .1f", 1024 * Byte, "1.0 KiB"},
+		{"%10s", Kilobyte, "       1kB"},
+		{"%-10s|", Kilobyte, "1kB       |"},
+	}
+	for _, test := range tests {
+		if found := fmt.Sprintf(test.format, test.sz); found != test.str {
+			t.Errorf("Sprintf(%q, %d): expected: %q, found: %q", test.format, test.sz, test.str, found)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	if found := (12345678 * Byte).Render('f', 2, false); found != "11.77MiB" {
+		t.Errorf("Render: expected: %q, found: %q", "11.77MiB", found)
+	}
+}
+
+func TestSIAndIEC(t *testing.T) {
+	if found := Kibibyte.SI().String(); found != "1.02kB" {
+		t.Errorf("Kibibyte.SI(): expected: %q, found: %q", "1.02kB", found)
+	}
+	if found := Kilobyte.IEC().String(); found != "1000B" {
+		t.Errorf("Kilobyte.IEC(): expected: %q, found: %q", "1000B", found)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	tests := []string{
+		"1zb", "1yb", "20eb",
+		"1e30",
+		"100000000000000000000",   // 100EB
+		"18446744073709551616",    // 2^64
+		"18.446744073709551615eb", // MaxUint64 bytes, which rounds up to 2^64 in float64
+	}
+	for _, str := range tests {
+		if _, err := Parse(str); err != ErrSizeOverflow {
+			t.Errorf("Parse(%q): expected: %v, found: %v", str, ErrSizeOverflow, err)
+		}
+	}
+}
+
+func TestExaUnits(t *testing.T) {
+	if found := Exabyte.String(); found != "1EB" {
+		t.Errorf("Exabyte.String(): expected: %q, found: %q", "1EB", found)
+	}
+	if found := Exbibyte.String(); found != "1EiB" {
+		t.Errorf("Exbibyte.String(): expected: %q, found: %q", "1EiB", found)
+	}
+	if found, err := Parse("1EiB"); err != nil || found != Exbibyte {
+		t.Errorf("Parse(%q): expected: %q, found: %q, err: %v", "1EiB", Exbibyte, found, err)
+	}
+}
+
+func TestParseBig(t *testing.T) {
+	tests := []struct {
+		str string
+		val *big.Int
+	}{
+		{"1zb", new(big.Int).Mul(big.NewInt(1000), new(big.Int).Exp(big.NewInt(1000), big.NewInt(6), nil))},
+		{"1yib", new(big.Int).Exp(big.NewInt(1024), big.NewInt(8), nil)},
+		{"1gb", big.NewInt(int64(Gigabyte))},
+	}
+	for _, test := range tests {
+		found, err := ParseBig(test.str)
+		if err != nil {
+			t.Fatalf("ParseBig(%q): unexpected error: %v", test.str, err)
+		}
+		if found.Cmp(test.val) != 0 {
+			t.Errorf("ParseBig(%q): expected: %s, found: %s", test.str, test.val, found)
+		}
+	}
+}
+
+func TestFormatBig(t *testing.T) {
+	tests := []struct {
+		val  *big.Int
+		base int
+		str  string
+	}{
+		{big.NewInt(int64(Gigabyte)), 10, "1GB"},
+		{new(big.Int).Exp(big.NewInt(1000), big.NewInt(7), nil), 10, "1ZB"},
+		{new(big.Int).Exp(big.NewInt(1024), big.NewInt(8), nil), 2, "1YiB"},
+		{new(big.Int).Lsh(big.NewInt(1), 200), 2, "1329227995784915872903807060280344576YiB"},
+	}
+	for _, test := range tests {
+		if found := FormatBig(test.val, test.base); found != test.str {
+			t.Errorf("FormatBig(%s, %d): expected: %q, found: %q", test.val, test.base, test.str, found)
+		}
+	}
+}
+
+func TestParseFlexible(t *testing.T) {
+	tests := []struct {
+		str string
+		sz  Size
+	}{
+		{"42 MiB", 42 * Mebibyte},
+		{"1,005.03 MB", Size(1005030000)},
+		{"42M", 42 * Megabyte},
+		{"42Mi", 42 * Mebibyte},
+		{"42MIB", 42 * Mebibyte},
+		{"  1 GB  ", Gigabyte},
+		{".5GB", Size(0.5 * float64(Gigabyte))},
+		{"5.GB", 5 * Gigabyte},
+	}
+	for _, test := range tests {
+		found, err := Parse(test.str)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", test.str, err)
+		}
+		if found != test.sz {
+			t.Errorf("Parse(%q): expected: %q, found: %q", test.str, test.sz, found)
+		}
+	}
+}
+
+func TestPerAndRateString(t *testing.T) {
+	tests := []struct {
+		sz  Size
+		d   time.Duration
+		str string
+	}{
+		{Size(12.5 * float64(Mebibyte)), time.Second, "12.5MiB/s"},
+		{800 * Kilobyte, time.Second, "800kB/s"},
+		{Gigabyte, time.Minute, "15.89MiB/s"},
+	}
+	for _, test := range tests {
+		if found := test.sz.Per(test.d).String(); found != test.str {
+			t.Errorf("%q.Per(%s): expected: %q, found: %q", test.sz, test.d, test.str, found)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		str string
+		sz  Size
+		d   time.Duration
+	}{
+		{"12.5MiB/s", Size(12.5 * float64(Mebibyte)), time.Second},
+		{"800kB/sec", 800 * Kilobyte, time.Second},
+		{"1.2GB/min", Size(1.2 * float64(Gigabyte)), time.Minute},
+	}
+	for _, test := range tests {
+		found, err := ParseRate(test.str)
+		if err != nil {
+			t.Fatalf("ParseRate(%q): unexpected error: %v", test.str, err)
+		}
+		want := test.sz.Per(test.d)
+		if found != want {
+			t.Errorf("ParseRate(%q): expected: %v, found: %v", test.str, want, found)
+		}
+	}
+}
+
+func TestFlagRate(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	r := FlagVarRate(fs, new(Rate), "rate", 0, "bandwidth limit")
+	if err := fs.Parse([]string{"-rate", "10MB/s"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (10 * Megabyte).Per(time.Second); *r != want {
+		t.Errorf("FlagVarRate: expected: %v, found: %v", want, *r)
+	}
+}
+
+func TestTextMarshaling(t *testing.T) {
+	text, err := Gibibyte.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(text) != "1GiB" {
+		t.Errorf("MarshalText: expected: %q, found: %q", "1GiB", text)
+	}
+	var sz Size
+	if err := sz.UnmarshalText(text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sz != Gibibyte {
+		t.Errorf("UnmarshalText(%q): expected: %q, found: %q", text, Gibibyte, sz)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type config struct {
+		Quota Size `json:"quota"`
+	}
+
+	data, err := json.Marshal(config{Quota: Mebibyte})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != `{"quota":"1MiB"}` {
+		t.Errorf("Marshal: expected: %q, found: %q", `{"quota":"1MiB"}`, data)
+	}
+
+	var fromString config
+	if err := json.Unmarshal(data, &fromString); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromString.Quota != Mebibyte {
+		t.Errorf("Unmarshal(string): expected: %q, found: %q", Mebibyte, fromString.Quota)
+	}
+
+	var fromNumber config
+	if err := json.Unmarshal([]byte(`{"quota":1048576}`), &fromNumber); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromNumber.Quota != Mebibyte {
+		t.Errorf("Unmarshal(number): expected: %q, found: %q", Mebibyte, fromNumber.Quota)
+	}
+
+	fromNull := config{Quota: Gigabyte}
+	if err := json.Unmarshal([]byte(`{"quota":null}`), &fromNull); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromNull.Quota != Gigabyte {
+		t.Errorf("Unmarshal(null): expected no-op leaving %q, found: %q", Gigabyte, fromNull.Quota)
+	}
+}
+
+func TestArithmeticSaturation(t *testing.T) {
+	const max = Size(math.MaxUint64)
+	if found := max.Add(Gigabyte); found != max {
+		t.Errorf("MaxUint64.Add(Gigabyte): expected: %d, found: %d", max, found)
+	}
+	if found := Gigabyte.Sub(Terabyte); found != 0 {
+		t.Errorf("Gigabyte.Sub(Terabyte): expected: 0, found: %d", found)
+	}
+	if found := max.Mul(2); found != max {
+		t.Errorf("MaxUint64.Mul(2): expected: %d, found: %d", max, found)
+	}
+	if found := Gigabyte.Mul(-1); found != 0 {
+		t.Errorf("Gigabyte.Mul(-1): expected: 0, found: %d", found)
+	}
+	if found := Gigabyte.Mul(1.5); found != 1500*Megabyte {
+		t.Errorf("Gigabyte.Mul(1.5): expected: %d, found: %d", 1500*Megabyte, found)
+	}
+}
+
+func TestDivRatioClampCmp(t *testing.T) {
+	if found := Gigabyte.Div(4); found != 250*float64(Megabyte) {
+		t.Errorf("Gigabyte.Div(4): expected: %v, found: %v", 250*float64(Megabyte), found)
+	}
+	if found := Gigabyte.Ratio(Megabyte); found != 1000 {
+		t.Errorf("Gigabyte.Ratio(Megabyte): expected: 1000, found: %v", found)
+	}
+	if found := (500 * Megabyte).Clamp(Gigabyte, 2*Gigabyte); found != Gigabyte {
+		t.Errorf("Clamp below min: expected: %d, found: %d", Gigabyte, found)
+	}
+	if found := (3 * Gigabyte).Clamp(Gigabyte, 2*Gigabyte); found != 2*Gigabyte {
+		t.Errorf("Clamp above max: expected: %d, found: %d", 2*Gigabyte, found)
+	}
+	if Gigabyte.Cmp(Megabyte) != 1 || Megabyte.Cmp(Gigabyte) != -1 || Gigabyte.Cmp(Gigabyte) != 0 {
+		t.Errorf("Cmp: unexpected ordering result")
+	}
+}
+
 func mustParse(s string) Size {
 	sz, err := Parse(s)
 	if err != nil {